@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	maxBatchURLs       = 50
+	maxBatchConcurrent = 8
+)
+
+// BatchFetchRequest is the body for POST /fetch/batch.
+type BatchFetchRequest struct {
+	URLs []string `json:"urls"`
+}
+
+// handleFetchBatch fetches metadata for a batch of URLs with bounded
+// concurrency, writing each FetchResponse as newline-delimited JSON as
+// soon as it's ready instead of buffering the whole batch in memory.
+func (ms *MetadataService) handleFetchBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req BatchFetchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if len(req.URLs) == 0 {
+		http.Error(w, "urls is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.URLs) > maxBatchURLs {
+		http.Error(w, "too many urls in one batch", http.StatusBadRequest)
+		return
+	}
+
+	flusher, _ := w.(http.Flusher)
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	var writeMu sync.Mutex
+	enc := json.NewEncoder(w)
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(maxBatchConcurrent)
+
+	for _, u := range req.URLs {
+		u := u
+		g.Go(func() error {
+			metadata, cached, shared, err := ms.fetchMetadataShared(gctx, u)
+			if err != nil {
+				metadata = &URLMetadata{URL: u, Error: err.Error()}
+			}
+
+			writeMu.Lock()
+			defer writeMu.Unlock()
+			if encErr := enc.Encode(FetchResponse{Metadata: metadata, Cached: cached, Shared: shared}); encErr != nil {
+				return encErr
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+			return nil
+		})
+	}
+
+	// Errors from individual fetches are already encoded into their own
+	// response lines; g.Wait's error only reflects write/context failures,
+	// which can't be surfaced via status code since headers are already
+	// sent, so there's nothing further to do with it here.
+	_ = g.Wait()
+}
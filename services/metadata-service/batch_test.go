@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandleFetchBatch_RejectsTooManyURLs(t *testing.T) {
+	ms := NewMetadataService()
+
+	urls := make([]string, maxBatchURLs+1)
+	for i := range urls {
+		urls[i] = "https://example.com"
+	}
+	body, _ := json.Marshal(BatchFetchRequest{URLs: urls})
+
+	req := httptest.NewRequest(http.MethodPost, "/fetch/batch", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	ms.handleFetchBatch(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestHandleFetchBatch_StreamsOneLinePerURL(t *testing.T) {
+	ms := NewMetadataService()
+
+	urls := []string{"http://127.0.0.1/a", "http://127.0.0.1/b"}
+	body, _ := json.Marshal(BatchFetchRequest{URLs: urls})
+
+	req := httptest.NewRequest(http.MethodPost, "/fetch/batch", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	ms.handleFetchBatch(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("Content-Type = %q, want application/x-ndjson", ct)
+	}
+
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	if len(lines) != len(urls) {
+		t.Fatalf("got %d NDJSON lines, want %d", len(lines), len(urls))
+	}
+	for _, line := range lines {
+		var resp FetchResponse
+		if err := json.Unmarshal([]byte(line), &resp); err != nil {
+			t.Errorf("failed to decode NDJSON line %q: %v", line, err)
+		}
+		if resp.Metadata.Error == "" {
+			t.Errorf("expected loopback fetch to be rejected by URLPolicy, got no error for %q", resp.Metadata.URL)
+		}
+	}
+}
+
+// TestHandleFetchBatch_ReusesCachedResult guards against handleFetchBatch
+// re-fetching a URL that's already cached: it must report Cached on a URL
+// that was primed via a prior /fetch (or batch) call, not hit upstream.
+func TestHandleFetchBatch_ReusesCachedResult(t *testing.T) {
+	ms := NewMetadataService()
+	ms.cache.Set("https://example.com/cached", &URLMetadata{
+		URL:   "https://example.com/cached",
+		Title: "Example",
+	}, defaultSuccessTTL)
+
+	body, _ := json.Marshal(BatchFetchRequest{URLs: []string{"https://example.com/cached"}})
+	req := httptest.NewRequest(http.MethodPost, "/fetch/batch", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	ms.handleFetchBatch(w, req)
+
+	var resp FetchResponse
+	if err := json.Unmarshal(bytes.TrimSpace(w.Body.Bytes()), &resp); err != nil {
+		t.Fatalf("failed to decode NDJSON line: %v", err)
+	}
+	if !resp.Cached {
+		t.Errorf("Cached = false, want true for a URL already in the cache")
+	}
+	if resp.Metadata.Title != "Example" {
+		t.Errorf("Metadata.Title = %q, want %q (expected the cached result, not a fresh fetch)", resp.Metadata.Title, "Example")
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"container/list"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxCacheEntries = 10000
+	defaultMaxCacheBytes   = 64 * 1024 * 1024
+	defaultSuccessTTL      = 24 * time.Hour
+	defaultNegativeTTL     = 5 * time.Minute
+)
+
+// CacheStats is a snapshot of cache behavior, exposed on /health or /stats
+// so operators can tell whether the cache is actually absorbing load.
+type CacheStats struct {
+	Hits      int64 `json:"hits"`
+	Misses    int64 `json:"misses"`
+	Evictions int64 `json:"evictions"`
+	Entries   int   `json:"entries"`
+	SizeBytes int64 `json:"size_bytes"`
+}
+
+// cacheEntry is the value stored in the LRU list.
+type cacheEntry struct {
+	url       string
+	metadata  *URLMetadata
+	expiresAt time.Time
+	size      int64
+}
+
+// Cache is an LRU, byte-size-bounded cache of fetched metadata. It is
+// bounded along two axes independently: MaxEntries caps the item count and
+// MaxBytes caps the total approximate size of cached metadata, whichever is
+// hit first evicts the least-recently-used entry.
+type Cache struct {
+	mu         sync.Mutex
+	items      map[string]*list.Element
+	order      *list.List // front = most recently used
+	maxEntries int
+	maxBytes   int64
+	curBytes   int64
+
+	hits      int64
+	misses    int64
+	evictions int64
+}
+
+// NewCache returns an empty Cache bounded by maxEntries items and maxBytes
+// of approximate metadata size. A zero value for either disables that
+// particular bound.
+func NewCache(maxEntries int, maxBytes int64) *Cache {
+	return &Cache{
+		items:      make(map[string]*list.Element),
+		order:      list.New(),
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+	}
+}
+
+// Get returns the cached metadata for url if present and unexpired,
+// promoting it to most-recently-used.
+func (c *Cache) Get(url string) (*URLMetadata, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, exists := c.items[url]
+	if !exists {
+		c.misses++
+		return nil, false
+	}
+
+	entry := el.Value.(*cacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.removeElement(el)
+		c.misses++
+		return nil, false
+	}
+
+	c.order.MoveToFront(el)
+	c.hits++
+	return entry.metadata, true
+}
+
+// Set stores metadata for url with the given TTL, evicting
+// least-recently-used entries as needed to stay within bounds.
+func (c *Cache) Set(url string, metadata *URLMetadata, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	size := approxSize(metadata)
+
+	if el, exists := c.items[url]; exists {
+		c.removeElement(el)
+	}
+
+	entry := &cacheEntry{
+		url:       url,
+		metadata:  metadata,
+		expiresAt: time.Now().Add(ttl),
+		size:      size,
+	}
+	el := c.order.PushFront(entry)
+	c.items[url] = el
+	c.curBytes += size
+
+	c.evictLocked()
+}
+
+// evictLocked removes least-recently-used entries until the cache is back
+// within its configured bounds. Caller must hold c.mu.
+func (c *Cache) evictLocked() {
+	for {
+		overEntries := c.maxEntries > 0 && len(c.items) > c.maxEntries
+		overBytes := c.maxBytes > 0 && c.curBytes > c.maxBytes
+		if !overEntries && !overBytes {
+			return
+		}
+		back := c.order.Back()
+		if back == nil {
+			return
+		}
+		c.removeElement(back)
+		c.evictions++
+	}
+}
+
+// removeElement deletes an element from both the map and the list and
+// adjusts curBytes. Caller must hold c.mu.
+func (c *Cache) removeElement(el *list.Element) {
+	entry := el.Value.(*cacheEntry)
+	delete(c.items, entry.url)
+	c.order.Remove(el)
+	c.curBytes -= entry.size
+}
+
+// Cleanup sweeps expired entries proactively; it is not required for
+// correctness (Get already checks expiry) but keeps memory from being held
+// by cold, expired entries between accesses.
+func (c *Cache) Cleanup() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	var next *list.Element
+	for el := c.order.Front(); el != nil; el = next {
+		next = el.Next()
+		if now.After(el.Value.(*cacheEntry).expiresAt) {
+			c.removeElement(el)
+		}
+	}
+}
+
+// Stats returns a point-in-time snapshot of cache behavior.
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return CacheStats{
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Evictions: c.evictions,
+		Entries:   len(c.items),
+		SizeBytes: c.curBytes,
+	}
+}
+
+// approxSize estimates the in-memory footprint of cached metadata by
+// marshaling it to JSON; this is cheap relative to a fetch and close enough
+// for cache-sizing purposes.
+func approxSize(metadata *URLMetadata) int64 {
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return 0
+	}
+	return int64(len(data))
+}
+
+// cacheTTLForResponse picks the TTL for a successful fetch, honoring the
+// upstream's Cache-Control: max-age or Expires header when present and
+// falling back to defaultTTL otherwise.
+func cacheTTLForResponse(header http.Header, defaultTTL time.Duration) time.Duration {
+	if cc := header.Get("Cache-Control"); cc != "" {
+		for _, directive := range strings.Split(cc, ",") {
+			directive = strings.TrimSpace(directive)
+			if strings.HasPrefix(directive, "no-store") || strings.HasPrefix(directive, "no-cache") {
+				return defaultNegativeTTL
+			}
+			if strings.HasPrefix(directive, "max-age=") {
+				secs, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age="))
+				if err == nil && secs >= 0 {
+					return time.Duration(secs) * time.Second
+				}
+			}
+		}
+	}
+
+	if exp := header.Get("Expires"); exp != "" {
+		if t, err := http.ParseTime(exp); err == nil {
+			if ttl := time.Until(t); ttl > 0 {
+				return ttl
+			}
+		}
+	}
+
+	return defaultTTL
+}
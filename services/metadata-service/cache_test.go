@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewCache(2, 0)
+
+	c.Set("a", &URLMetadata{URL: "a"}, time.Hour)
+	c.Set("b", &URLMetadata{URL: "b"}, time.Hour)
+
+	if _, ok := c.Get("a"); !ok {
+		t.Fatalf("expected a to still be cached")
+	}
+
+	c.Set("c", &URLMetadata{URL: "c"}, time.Hour)
+
+	if _, ok := c.Get("b"); ok {
+		t.Errorf("expected b to have been evicted as least-recently-used")
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Errorf("expected a to survive eviction since it was accessed more recently")
+	}
+	if _, ok := c.Get("c"); !ok {
+		t.Errorf("expected c to be cached")
+	}
+
+	stats := c.Stats()
+	if stats.Evictions != 1 {
+		t.Errorf("Evictions = %d, want 1", stats.Evictions)
+	}
+}
+
+func TestCache_ExpiresEntries(t *testing.T) {
+	c := NewCache(10, 0)
+	c.Set("a", &URLMetadata{URL: "a"}, -time.Second)
+
+	if _, ok := c.Get("a"); ok {
+		t.Errorf("expected expired entry to miss")
+	}
+}
+
+func TestCacheTTLForResponse_MaxAge(t *testing.T) {
+	h := http.Header{}
+	h.Set("Cache-Control", "public, max-age=120")
+
+	ttl := cacheTTLForResponse(h, defaultSuccessTTL)
+	if ttl != 120*time.Second {
+		t.Errorf("cacheTTLForResponse() = %v, want 120s", ttl)
+	}
+}
+
+func TestCacheTTLForResponse_NoStoreFallsBackToNegativeTTL(t *testing.T) {
+	h := http.Header{}
+	h.Set("Cache-Control", "no-store")
+
+	ttl := cacheTTLForResponse(h, defaultSuccessTTL)
+	if ttl != defaultNegativeTTL {
+		t.Errorf("cacheTTLForResponse() = %v, want %v", ttl, defaultNegativeTTL)
+	}
+}
+
+func TestCacheTTLForResponse_DefaultWhenNoHeaders(t *testing.T) {
+	ttl := cacheTTLForResponse(http.Header{}, defaultSuccessTTL)
+	if ttl != defaultSuccessTTL {
+		t.Errorf("cacheTTLForResponse() = %v, want %v", ttl, defaultSuccessTTL)
+	}
+}
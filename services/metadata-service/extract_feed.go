@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gilliek/go-opml/opml"
+	"github.com/mmcdole/gofeed"
+)
+
+// Feed holds a summary of an RSS/Atom/OPML document: enough for a front-end
+// to render a "this links to a feed with N items" card without fetching
+// the whole thing itself.
+type Feed struct {
+	Kind       string `json:"kind"` // "rss", "atom", or "opml"
+	Title      string `json:"title,omitempty"`
+	ItemCount  int    `json:"item_count"`
+	LatestItem string `json:"latest_item,omitempty"`
+}
+
+// feedExtractor recognizes RSS, Atom, and OPML documents by content type or
+// sniffed root element and summarizes them into a Feed.
+type feedExtractor struct{}
+
+func (feedExtractor) CanHandle(contentType string, sniff []byte) bool {
+	ct := strings.ToLower(contentType)
+	if strings.Contains(ct, "rss") || strings.Contains(ct, "atom") || strings.Contains(ct, "opml") {
+		return true
+	}
+
+	s := string(bytes.ToLower(bytes.TrimSpace(sniff)))
+	return strings.Contains(s, "<rss") || strings.Contains(s, "<feed") || strings.Contains(s, "<opml")
+}
+
+func (feedExtractor) Extract(_ context.Context, rawURL string, body []byte) (*URLMetadata, error) {
+	if bytes.Contains(bytes.ToLower(body[:min(len(body), 4096)]), []byte("<opml")) {
+		return extractOPML(rawURL, body)
+	}
+
+	fp := gofeed.NewParser()
+	parsed, err := fp.ParseString(string(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse feed: %w", err)
+	}
+
+	feed := &Feed{
+		Kind:      strings.ToLower(string(parsed.FeedType)),
+		Title:     parsed.Title,
+		ItemCount: len(parsed.Items),
+	}
+	if len(parsed.Items) > 0 {
+		feed.LatestItem = parsed.Items[0].Title
+	}
+
+	return &URLMetadata{
+		URL:         rawURL,
+		Title:       parsed.Title,
+		Description: parsed.Description,
+		Feed:        feed,
+	}, nil
+}
+
+func extractOPML(rawURL string, body []byte) (*URLMetadata, error) {
+	doc, err := opml.NewOPML(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse OPML: %w", err)
+	}
+
+	feed := &Feed{
+		Kind:      "opml",
+		Title:     doc.Head.Title,
+		ItemCount: len(doc.Body.Outlines),
+	}
+	if len(doc.Body.Outlines) > 0 {
+		feed.LatestItem = doc.Body.Outlines[0].Title
+	}
+
+	return &URLMetadata{
+		URL:   rawURL,
+		Title: doc.Head.Title,
+		Feed:  feed,
+	}, nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"net/http"
+	"strings"
+
+	"github.com/rwcarlsen/goexif/exif"
+)
+
+// ImageMeta holds dimensions, MIME type, and any EXIF fields we recognized
+// for a fetched image.
+type ImageMeta struct {
+	Width       int    `json:"width,omitempty"`
+	Height      int    `json:"height,omitempty"`
+	MIMEType    string `json:"mime_type,omitempty"`
+	CameraMake  string `json:"camera_make,omitempty"`
+	CameraModel string `json:"camera_model,omitempty"`
+	DateTaken   string `json:"date_taken,omitempty"`
+	Orientation string `json:"orientation,omitempty"`
+}
+
+// imageExtractor decodes image dimensions via the standard library's
+// registered decoders and pulls a handful of common EXIF fields when
+// present.
+type imageExtractor struct{}
+
+func (imageExtractor) CanHandle(contentType string, sniff []byte) bool {
+	if strings.HasPrefix(contentType, "image/") {
+		return true
+	}
+	return strings.HasPrefix(http.DetectContentType(sniff), "image/")
+}
+
+func (imageExtractor) Extract(_ context.Context, rawURL string, body []byte) (*URLMetadata, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	meta := &ImageMeta{
+		Width:    cfg.Width,
+		Height:   cfg.Height,
+		MIMEType: "image/" + format,
+	}
+
+	if x, err := exif.Decode(bytes.NewReader(body)); err == nil {
+		if tag, err := x.Get(exif.Make); err == nil {
+			meta.CameraMake, _ = tag.StringVal()
+		}
+		if tag, err := x.Get(exif.Model); err == nil {
+			meta.CameraModel, _ = tag.StringVal()
+		}
+		if tag, err := x.Get(exif.DateTimeOriginal); err == nil {
+			meta.DateTaken, _ = tag.StringVal()
+		}
+		if tag, err := x.Get(exif.Orientation); err == nil {
+			meta.Orientation = tag.String()
+		}
+	}
+
+	return &URLMetadata{
+		URL:       rawURL,
+		ImageMeta: meta,
+	}, nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func TestImageExtractor_ParsesDimensionsAndMIMEType(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 3))
+	img.Set(0, 0, color.RGBA{R: 255, A: 255})
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("png.Encode() error = %v", err)
+	}
+	body := buf.Bytes()
+
+	e := imageExtractor{}
+	if !e.CanHandle("image/png", body) {
+		t.Fatalf("expected imageExtractor to claim image/png content")
+	}
+
+	metadata, err := e.Extract(context.Background(), "https://example.com/pic.png", body)
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	if metadata.ImageMeta.Width != 4 || metadata.ImageMeta.Height != 3 {
+		t.Errorf("ImageMeta = %+v, want width=4 height=3", metadata.ImageMeta)
+	}
+	if metadata.ImageMeta.MIMEType != "image/png" {
+		t.Errorf("MIMEType = %q, want %q", metadata.ImageMeta.MIMEType, "image/png")
+	}
+}
+
+func TestImageExtractor_CanHandleSniffsContentTypeWhenHeaderMissing(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("png.Encode() error = %v", err)
+	}
+
+	e := imageExtractor{}
+	if !e.CanHandle("", buf.Bytes()) {
+		t.Fatalf("expected imageExtractor to sniff PNG content with no Content-Type header")
+	}
+	if e.CanHandle("text/html", []byte("<html></html>")) {
+		t.Fatalf("expected imageExtractor not to claim HTML content")
+	}
+}
+
+func TestImageExtractor_ExtractRejectsUndecodableBody(t *testing.T) {
+	e := imageExtractor{}
+	if _, err := e.Extract(context.Background(), "https://example.com/not-an-image", []byte("not an image")); err == nil {
+		t.Fatalf("expected Extract() to fail on non-image body")
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"strconv"
+)
+
+// PDFMeta holds metadata pulled from a PDF's document information
+// dictionary and page tree.
+type PDFMeta struct {
+	Title  string `json:"title,omitempty"`
+	Author string `json:"author,omitempty"`
+	Pages  int    `json:"pages,omitempty"`
+}
+
+// pdfExtractor pulls Title/Author/page-count out of a PDF without a full
+// parser: the document information dictionary and page objects are plain
+// ASCII/latin-1 text inside the file, so a handful of regexes over the raw
+// bytes is enough for metadata purposes and avoids pulling in a full PDF
+// rendering library for this.
+type pdfExtractor struct{}
+
+var (
+	pdfTitleRe  = regexp.MustCompile(`/Title\s*\(([^)]*)\)`)
+	pdfAuthorRe = regexp.MustCompile(`/Author\s*\(([^)]*)\)`)
+	pdfTypeRe   = regexp.MustCompile(`/Type\s*/Page[^s]`)
+)
+
+func (pdfExtractor) CanHandle(contentType string, sniff []byte) bool {
+	if bytes.Contains([]byte(contentType), []byte("application/pdf")) {
+		return true
+	}
+	return bytes.HasPrefix(sniff, []byte("%PDF-"))
+}
+
+func (pdfExtractor) Extract(_ context.Context, rawURL string, body []byte) (*URLMetadata, error) {
+	meta := &PDFMeta{
+		Pages: len(pdfTypeRe.FindAll(body, -1)),
+	}
+
+	if m := pdfTitleRe.FindSubmatch(body); m != nil {
+		meta.Title = decodePDFString(m[1])
+	}
+	if m := pdfAuthorRe.FindSubmatch(body); m != nil {
+		meta.Author = decodePDFString(m[1])
+	}
+
+	return &URLMetadata{
+		URL:     rawURL,
+		Title:   meta.Title,
+		PDFMeta: meta,
+	}, nil
+}
+
+// decodePDFString unescapes the small set of backslash escapes PDF literal
+// strings use (\n, \r, \t, \(, \), \\) and octal escapes; anything else is
+// passed through as-is.
+func decodePDFString(raw []byte) string {
+	var out bytes.Buffer
+	for i := 0; i < len(raw); i++ {
+		if raw[i] != '\\' || i == len(raw)-1 {
+			out.WriteByte(raw[i])
+			continue
+		}
+		i++
+		switch raw[i] {
+		case 'n':
+			out.WriteByte('\n')
+		case 'r':
+			out.WriteByte('\r')
+		case 't':
+			out.WriteByte('\t')
+		case '(', ')', '\\':
+			out.WriteByte(raw[i])
+		default:
+			if raw[i] >= '0' && raw[i] <= '7' && i+2 < len(raw) {
+				if n, err := strconv.ParseInt(string(raw[i:i+3]), 8, 16); err == nil {
+					out.WriteByte(byte(n))
+					i += 2
+					continue
+				}
+			}
+			out.WriteByte(raw[i])
+		}
+	}
+	return out.String()
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+)
+
+// sniffLen is how many leading bytes of a response body we sniff for
+// content-type detection, matching net/http's own DetectContentType window.
+const sniffLen = 512
+
+// Extractor handles a single content family (HTML, PDF, image, feed, ...).
+// MetadataService dispatches each fetched body to the first registered
+// Extractor whose CanHandle returns true, falling back to the HTML
+// extractor already built into fetchMetadata when none match.
+type Extractor interface {
+	// CanHandle reports whether this extractor applies to a response with
+	// the given Content-Type header and sniffed leading bytes.
+	CanHandle(contentType string, sniff []byte) bool
+	// Extract parses body (the full response body) into a URLMetadata for
+	// rawURL.
+	Extract(ctx context.Context, rawURL string, body []byte) (*URLMetadata, error)
+}
+
+// RegisterExtractor adds e to the dispatch list. Extractors are tried in
+// registration order, so more specific extractors should be registered
+// before general ones.
+func (ms *MetadataService) RegisterExtractor(e Extractor) {
+	ms.extractors = append(ms.extractors, e)
+}
+
+// dispatchExtractor returns the first registered Extractor that claims the
+// given content type and sniffed bytes, or nil if none match (meaning the
+// caller should fall back to HTML parsing).
+func (ms *MetadataService) dispatchExtractor(contentType string, sniff []byte) Extractor {
+	for _, e := range ms.extractors {
+		if e.CanHandle(contentType, sniff) {
+			return e
+		}
+	}
+	return nil
+}
+
+// isHTMLLike reports whether contentType/sniff look like markup that the
+// default goquery-based pipeline should handle, as opposed to one of the
+// typed extractors.
+func isHTMLLike(contentType string, sniff []byte) bool {
+	ct := strings.ToLower(contentType)
+	if strings.Contains(ct, "html") || strings.Contains(ct, "xhtml") {
+		return true
+	}
+	if ct == "" {
+		trimmed := bytes.TrimSpace(sniff)
+		return bytes.HasPrefix(trimmed, []byte("<"))
+	}
+	return false
+}
+
+// registerBuiltinExtractors wires up the PDF, image, and feed extractors
+// that ship with the service.
+func (ms *MetadataService) registerBuiltinExtractors() {
+	ms.RegisterExtractor(&pdfExtractor{})
+	ms.RegisterExtractor(&imageExtractor{})
+	ms.RegisterExtractor(&feedExtractor{})
+}
+
+// sniffBody reads up to sniffLen bytes for content sniffing without
+// consuming them from data.
+func sniffBody(data []byte) []byte {
+	if len(data) > sniffLen {
+		return data[:sniffLen]
+	}
+	return data
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPDFExtractor_ParsesTitleAuthorAndPageCount(t *testing.T) {
+	body := []byte(`%PDF-1.4
+1 0 obj << /Type /Catalog >> endobj
+2 0 obj << /Type /Page /Parent 1 0 R >> endobj
+3 0 obj << /Type /Page /Parent 1 0 R >> endobj
+trailer << /Info 4 0 R >>
+4 0 obj << /Title (Annual Report) /Author (Jane Doe) >> endobj
+%%EOF`)
+
+	e := pdfExtractor{}
+	if !e.CanHandle("application/pdf", body) {
+		t.Fatalf("expected pdfExtractor to claim %%PDF- content")
+	}
+
+	metadata, err := e.Extract(context.Background(), "https://example.com/report.pdf", body)
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	if metadata.PDFMeta.Title != "Annual Report" || metadata.PDFMeta.Author != "Jane Doe" {
+		t.Errorf("PDFMeta = %+v, want title=Annual Report author=Jane Doe", metadata.PDFMeta)
+	}
+	if metadata.PDFMeta.Pages != 2 {
+		t.Errorf("Pages = %d, want 2", metadata.PDFMeta.Pages)
+	}
+}
+
+func TestFeedExtractor_ParsesRSS(t *testing.T) {
+	body := []byte(`<?xml version="1.0"?>
+<rss version="2.0"><channel>
+	<title>Example Feed</title>
+	<description>An example</description>
+	<item><title>First post</title></item>
+	<item><title>Second post</title></item>
+</channel></rss>`)
+
+	e := feedExtractor{}
+	if !e.CanHandle("application/rss+xml", body) {
+		t.Fatalf("expected feedExtractor to claim RSS content")
+	}
+
+	metadata, err := e.Extract(context.Background(), "https://example.com/feed.xml", body)
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	if metadata.Feed.ItemCount != 2 || metadata.Feed.LatestItem != "First post" {
+		t.Errorf("Feed = %+v, want item_count=2 latest_item=First post", metadata.Feed)
+	}
+}
+
+func TestFeedExtractor_ParsesOPML(t *testing.T) {
+	body := []byte(`<?xml version="1.0"?>
+<opml version="1.0">
+	<head><title>My Subscriptions</title></head>
+	<body>
+		<outline text="Feed A" title="Feed A" xmlUrl="https://a.example.com/feed"/>
+		<outline text="Feed B" title="Feed B" xmlUrl="https://b.example.com/feed"/>
+	</body>
+</opml>`)
+
+	e := feedExtractor{}
+	if !e.CanHandle("text/x-opml", body) {
+		t.Fatalf("expected feedExtractor to claim OPML content")
+	}
+
+	metadata, err := e.Extract(context.Background(), "https://example.com/subs.opml", body)
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	if metadata.Feed.Kind != "opml" || metadata.Feed.ItemCount != 2 {
+		t.Errorf("Feed = %+v, want kind=opml item_count=2", metadata.Feed)
+	}
+}
+
+func TestIsHTMLLike(t *testing.T) {
+	cases := []struct {
+		contentType string
+		sniff       string
+		want        bool
+	}{
+		{"text/html; charset=utf-8", "", true},
+		{"", "<!DOCTYPE html><html></html>", true},
+		{"application/pdf", "%PDF-1.4", false},
+		{"", "%PDF-1.4", false},
+	}
+	for _, c := range cases {
+		if got := isHTMLLike(c.contentType, []byte(c.sniff)); got != c.want {
+			t.Errorf("isHTMLLike(%q, %q) = %v, want %v", c.contentType, c.sniff, got, c.want)
+		}
+	}
+}
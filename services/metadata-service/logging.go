@@ -0,0 +1,16 @@
+package main
+
+import "go.uber.org/zap"
+
+// newLogger builds the service's structured logger. JSON output so the
+// service is operable behind a log pipeline in a cluster rather than
+// grepping plain-text lines.
+func newLogger() *zap.Logger {
+	logger, err := zap.NewProduction()
+	if err != nil {
+		// zap.NewProduction only fails on a broken encoder/sink config,
+		// which can't happen with the defaults above.
+		panic(err)
+	}
+	return logger
+}
@@ -1,44 +1,58 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"io"
+	"net"
 	"net/http"
+	neturl "net/url"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
 	"golang.org/x/net/html"
+	"golang.org/x/sync/singleflight"
 )
 
 type MetadataService struct {
-	cache      *Cache
-	httpClient *http.Client
-	mu         sync.RWMutex
-}
-
-type Cache struct {
-	items map[string]*CacheItem
-	mu    sync.RWMutex
-}
-
-type CacheItem struct {
-	Metadata  *URLMetadata
-	ExpiresAt time.Time
+	cache       *Cache
+	sfGroup     singleflight.Group
+	httpClient  *http.Client
+	urlPolicy   *URLPolicy
+	rateLimiter *HostRateLimiter
+	robotsCache *RobotsCache
+	extractors  []Extractor
+	logger      *zap.Logger
+	mu          sync.RWMutex
+
+	sfSharedCount int64
 }
 
 type URLMetadata struct {
-	URL         string `json:"url"`
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	SiteName    string `json:"site_name,omitempty"`
-	ImageURL    string `json:"image_url,omitempty"`
-	Error       string `json:"error,omitempty"`
+	URL         string     `json:"url"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	SiteName    string     `json:"site_name,omitempty"`
+	ImageURL    string     `json:"image_url,omitempty"`
+	Embed       *Embed     `json:"embed,omitempty"`
+	Article     *Article   `json:"article,omitempty"`
+	Product     *Product   `json:"product,omitempty"`
+	Video       *Video     `json:"video,omitempty"`
+	PDFMeta     *PDFMeta   `json:"pdf_meta,omitempty"`
+	ImageMeta   *ImageMeta `json:"image_meta,omitempty"`
+	Feed        *Feed      `json:"feed,omitempty"`
+	Error       string     `json:"error,omitempty"`
 }
 
 type FetchRequest struct {
@@ -47,112 +61,164 @@ type FetchRequest struct {
 
 type FetchResponse struct {
 	Metadata *URLMetadata `json:"metadata"`
-	Cached   bool         `json:"cached"`
+	// Cached reports whether Metadata was served from the cache rather than
+	// fetched. Shared reports the operationally distinct case of a fresh
+	// fetch whose result was coalesced with another in-flight request via
+	// singleflight; a caller can hit Shared on a URL it has never seen
+	// before simply by racing another caller, so the two must not be
+	// conflated.
+	Cached bool `json:"cached"`
+	Shared bool `json:"shared"`
 }
 
 func NewMetadataService() *MetadataService {
-	return &MetadataService{
-		cache: &Cache{
-			items: make(map[string]*CacheItem),
-		},
+	policy := NewURLPolicy()
+
+	ms := &MetadataService{
+		cache:       NewCache(defaultMaxCacheEntries, defaultMaxCacheBytes),
+		urlPolicy:   policy,
+		rateLimiter: NewHostRateLimiterFromEnv(),
+		robotsCache: NewRobotsCache(robotsCacheTTL),
 		httpClient: &http.Client{
 			Timeout: 10 * time.Second,
 			Transport: &http.Transport{
 				MaxIdleConns:        100,
 				MaxIdleConnsPerHost: 10,
 				IdleConnTimeout:     30 * time.Second,
+				DialContext: policy.SafeDialContext(&net.Dialer{
+					Timeout:   5 * time.Second,
+					KeepAlive: 30 * time.Second,
+				}),
 			},
+			CheckRedirect: policy.CheckRedirect,
 		},
+		logger: newLogger(),
 	}
+	ms.registerBuiltinExtractors()
+	return ms
 }
 
-func (c *Cache) Get(url string) (*URLMetadata, bool) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	item, exists := c.items[url]
-	if !exists {
-		return nil, false
-	}
-
-	if time.Now().After(item.ExpiresAt) {
-		delete(c.items, url)
-		return nil, false
+// fetchMetadata fetches and extracts metadata for rawURL, returning the TTL
+// the result should be cached for. Results carrying a URLMetadata.Error use
+// a short negative TTL so broken URLs aren't hammered; successful results
+// honor the upstream's Cache-Control/Expires headers when present.
+func (ms *MetadataService) fetchMetadata(ctx context.Context, rawURL string) (*URLMetadata, time.Duration, error) {
+	parsed, errCode, err := ms.urlPolicy.Validate(rawURL)
+	if err != nil {
+		return &URLMetadata{
+			URL:   rawURL,
+			Error: fmt.Sprintf("%s: %v", errCode, err),
+		}, defaultNegativeTTL, nil
 	}
 
-	return item.Metadata, true
-}
-
-func (c *Cache) Set(url string, metadata *URLMetadata, ttl time.Duration) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	c.items[url] = &CacheItem{
-		Metadata:  metadata,
-		ExpiresAt: time.Now().Add(ttl),
+	if !ms.rateLimiter.Allow(parsed.Hostname()) {
+		return &URLMetadata{
+			URL:   rawURL,
+			Error: fmt.Sprintf("%s: rate limit exceeded for host %s", ErrCodeRateLimited, parsed.Hostname()),
+		}, defaultNegativeTTL, nil
 	}
-}
 
-func (c *Cache) Cleanup() {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	now := time.Now()
-	for url, item := range c.items {
-		if now.After(item.ExpiresAt) {
-			delete(c.items, url)
-		}
+	if !ms.allowedByRobots(ctx, parsed) {
+		return &URLMetadata{
+			URL:   rawURL,
+			Error: fmt.Sprintf("%s: disallowed by robots.txt", ErrCodeBlockedHost),
+		}, defaultNegativeTTL, nil
 	}
-}
 
-func (ms *MetadataService) fetchMetadata(ctx context.Context, url string) (*URLMetadata, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", parsed.String(), nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, 0, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("User-Agent", "Lobsters-MetadataService/1.0")
 	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
 	req.Header.Set("Accept-Language", "en-US,en;q=0.5")
 
-	resp, err := ms.httpClient.Do(req)
+	resp, err := doFetchWithRetry(ctx, ms.httpClient, req)
 	if err != nil {
 		return &URLMetadata{
-			URL:   url,
-			Error: fmt.Sprintf("failed to fetch: %v", err),
-		}, nil
+			URL:   rawURL,
+			Error: fmt.Sprintf("%s: failed to fetch: %v", ErrCodeUpstreamError, err),
+		}, defaultNegativeTTL, nil
 	}
 	defer resp.Body.Close()
 
+	recordUpstreamStatus(resp.StatusCode)
+
 	if resp.StatusCode != http.StatusOK {
 		return &URLMetadata{
-			URL:   url,
-			Error: fmt.Sprintf("HTTP %d", resp.StatusCode),
-		}, nil
+			URL:   rawURL,
+			Error: fmt.Sprintf("%s: HTTP %d", ErrCodeUpstreamError, resp.StatusCode),
+		}, defaultNegativeTTL, nil
+	}
+
+	if cl := resp.Header.Get("Content-Length"); cl != "" {
+		if n, err := strconv.ParseInt(cl, 10, 64); err == nil && n > ms.urlPolicy.MaxResponseSize {
+			return &URLMetadata{
+				URL:   rawURL,
+				Error: fmt.Sprintf("%s: content-length %d exceeds limit", ErrCodeTooLarge, n),
+			}, defaultNegativeTTL, nil
+		}
 	}
 
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	data, err := io.ReadAll(io.LimitReader(resp.Body, ms.urlPolicy.MaxResponseSize+1))
 	if err != nil {
 		return &URLMetadata{
-			URL:   url,
-			Error: fmt.Sprintf("failed to parse HTML: %v", err),
-		}, nil
+			URL:   rawURL,
+			Error: fmt.Sprintf("%s: failed to read body: %v", ErrCodeUpstreamError, err),
+		}, defaultNegativeTTL, nil
+	}
+	if int64(len(data)) > ms.urlPolicy.MaxResponseSize {
+		return &URLMetadata{
+			URL:   rawURL,
+			Error: fmt.Sprintf("%s: response exceeds %d bytes", ErrCodeTooLarge, ms.urlPolicy.MaxResponseSize),
+		}, defaultNegativeTTL, nil
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	sniff := sniffBody(data)
+
+	if e := ms.dispatchExtractor(contentType, sniff); e != nil {
+		metadata, err := e.Extract(ctx, rawURL, data)
+		if err != nil {
+			return &URLMetadata{
+				URL:   rawURL,
+				Error: fmt.Sprintf("%s: %v", ErrCodeUpstreamError, err),
+			}, defaultNegativeTTL, nil
+		}
+		return metadata, cacheTTLForResponse(resp.Header, defaultSuccessTTL), nil
+	}
+
+	if !isHTMLLike(contentType, sniff) {
+		return &URLMetadata{
+			URL:   rawURL,
+			Error: fmt.Sprintf("%s: unsupported content type %q", ErrCodeUpstreamError, contentType),
+		}, defaultNegativeTTL, nil
+	}
+
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(data))
+	if err != nil {
+		return &URLMetadata{
+			URL:   rawURL,
+			Error: fmt.Sprintf("%s: failed to parse HTML: %v", ErrCodeUpstreamError, err),
+		}, defaultNegativeTTL, nil
 	}
 
 	metadata := &URLMetadata{
-		URL: url,
+		URL: rawURL,
 	}
 
 	metadata.Title = ms.extractTitle(doc)
 	metadata.Description = ms.extractDescription(doc)
 	metadata.SiteName = ms.extractSiteName(doc)
 	metadata.ImageURL = ms.extractImageURL(doc)
+	ms.mergeRichMetadata(ctx, doc, metadata)
 
 	if metadata.URL == "" {
-		metadata.URL = url
+		metadata.URL = rawURL
 	}
 
-	return metadata, nil
+	return metadata, cacheTTLForResponse(resp.Header, defaultSuccessTTL), nil
 }
 
 func (ms *MetadataService) extractTitle(doc *goquery.Document) string {
@@ -258,30 +324,105 @@ func (ms *MetadataService) handleFetch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cached, found := ms.cache.Get(req.URL)
-	if found {
-		json.NewEncoder(w).Encode(FetchResponse{
-			Metadata: cached,
-			Cached:   true,
-		})
-		return
-	}
+	start := time.Now()
+	host := requestHost(req.URL)
 
 	ctx, cancel := context.WithTimeout(r.Context(), 8*time.Second)
 	defer cancel()
 
-	metadata, err := ms.fetchMetadata(ctx, req.URL)
+	metadata, cached, shared, err := ms.fetchMetadataShared(ctx, req.URL)
 	if err != nil {
+		recordFetchResult("error")
+		ms.logger.Error("fetch failed", zap.String("url", req.URL), zap.Error(err))
 		http.Error(w, fmt.Sprintf("Failed to fetch metadata: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	ms.cache.Set(req.URL, metadata, 24*time.Hour)
+	result := "miss"
+	if cached {
+		result = "hit"
+	} else if metadata.Error != "" {
+		result = "error"
+	}
+	recordFetchResult(result)
+	metadataFetchDuration.Observe(time.Since(start).Seconds())
+	ms.logger.Info("fetch",
+		zap.String("url", req.URL),
+		zap.String("host", host),
+		zap.Bool("cached", cached),
+		zap.Bool("shared", shared),
+		zap.String("upstream_error", metadata.Error),
+		zap.Duration("duration", time.Since(start)),
+		zap.Int("bytes", approxSizeBytes(metadata)),
+	)
+
+	writeFetchResponse(w, metadata, cached, shared)
+}
 
+// writeFetchResponse writes a FetchResponse for metadata, mapping a cached
+// rate-limit result to 429 just like a fresh one: the error is baked into
+// metadata.Error regardless of whether it came from the cache or a live
+// fetch, so both paths must check it rather than only the live one.
+func writeFetchResponse(w http.ResponseWriter, metadata *URLMetadata, cached, shared bool) {
+	if strings.HasPrefix(metadata.Error, ErrCodeRateLimited+":") {
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}
 	json.NewEncoder(w).Encode(FetchResponse{
 		Metadata: metadata,
-		Cached:   false,
+		Cached:   cached,
+		Shared:   shared,
+	})
+}
+
+// requestHost extracts the host component of rawURL for metric labeling,
+// falling back to "unknown" for unparseable input rather than failing the
+// request over an observability concern.
+func requestHost(rawURL string) string {
+	u, err := neturl.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return "unknown"
+	}
+	return u.Hostname()
+}
+
+// approxSizeBytes reports the JSON-encoded size of metadata, used only for
+// the per-request byte-count log field.
+func approxSizeBytes(metadata *URLMetadata) int {
+	return int(approxSize(metadata))
+}
+
+// fetchMetadataShared returns a cached result for rawURL if one exists, and
+// otherwise fetches it, coalescing concurrent requests for the same URL via
+// singleflight and populating the cache with the result's TTL. Every caller
+// that wants cache reuse (handleFetch, handleFetchBatch) must go through
+// this method rather than fetching and checking the cache separately, so
+// the two stay consistent. The first returned bool reports whether the
+// result came from the cache; the second reports whether it was instead
+// produced by a fresh fetch that was coalesced with another in-flight
+// request for the same URL.
+func (ms *MetadataService) fetchMetadataShared(ctx context.Context, rawURL string) (*URLMetadata, bool, bool, error) {
+	if cached, found := ms.cache.Get(rawURL); found {
+		return cached, true, false, nil
+	}
+
+	v, err, shared := ms.sfGroup.Do(rawURL, func() (interface{}, error) {
+		metadata, ttl, err := ms.fetchMetadata(ctx, rawURL)
+		if err != nil {
+			return nil, err
+		}
+		ms.cache.Set(rawURL, metadata, ttl)
+		observeCacheSize(ms.cache)
+		return metadata, nil
 	})
+	if err != nil {
+		return nil, false, false, err
+	}
+	if shared {
+		atomic.AddInt64(&ms.sfSharedCount, 1)
+		metadataSingleflightSharedTotal.Inc()
+	}
+	return v.(*URLMetadata), false, shared, nil
 }
 
 func (ms *MetadataService) handleHealth(w http.ResponseWriter, r *http.Request) {
@@ -292,6 +433,19 @@ func (ms *MetadataService) handleHealth(w http.ResponseWriter, r *http.Request)
 	})
 }
 
+func (ms *MetadataService) handleStats(w http.ResponseWriter, r *http.Request) {
+	stats := ms.cache.Stats()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		CacheStats
+		SingleflightShared int64 `json:"singleflight_shared"`
+	}{
+		CacheStats:         stats,
+		SingleflightShared: atomic.LoadInt64(&ms.sfSharedCount),
+	})
+}
+
 func (ms *MetadataService) startCacheCleanup() {
 	ticker := time.NewTicker(1 * time.Hour)
 	go func() {
@@ -312,7 +466,10 @@ func main() {
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/fetch", service.handleFetch)
+	mux.HandleFunc("/fetch/batch", service.handleFetchBatch)
 	mux.HandleFunc("/health", service.handleHealth)
+	mux.HandleFunc("/stats", service.handleStats)
+	mux.Handle("/metrics", promhttp.Handler())
 
 	server := &http.Server{
 		Addr:         ":" + port,
@@ -323,9 +480,9 @@ func main() {
 	}
 
 	go func() {
-		log.Printf("Metadata service starting on port %s", port)
+		service.logger.Info("metadata service starting", zap.String("port", port))
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server failed: %v", err)
+			service.logger.Fatal("server failed", zap.Error(err))
 		}
 	}()
 
@@ -333,13 +490,13 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	log.Println("Shutting down server...")
+	service.logger.Info("shutting down server")
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	if err := server.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+		service.logger.Fatal("server forced to shutdown", zap.Error(err))
 	}
 
-	log.Println("Server exited")
+	service.logger.Info("server exited")
 }
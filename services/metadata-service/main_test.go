@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWriteFetchResponse_MapsRateLimitErrorTo429(t *testing.T) {
+	metadata := &URLMetadata{
+		URL:   "https://example.com",
+		Error: ErrCodeRateLimited + ": rate limit exceeded for host example.com",
+	}
+
+	w := httptest.NewRecorder()
+	writeFetchResponse(w, metadata, true, false)
+
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+	if got := w.Header().Get("Retry-After"); got == "" {
+		t.Errorf("expected Retry-After header to be set")
+	}
+
+	var resp FetchResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response body: %v", err)
+	}
+	if resp.Metadata.Error != metadata.Error {
+		t.Errorf("Metadata.Error = %q, want %q", resp.Metadata.Error, metadata.Error)
+	}
+}
+
+func TestWriteFetchResponse_NonRateLimitErrorStays200(t *testing.T) {
+	metadata := &URLMetadata{URL: "https://example.com", Title: "Example"}
+
+	w := httptest.NewRecorder()
+	writeFetchResponse(w, metadata, false, false)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+}
+
+// TestHandleFetch_RepeatedRateLimitedRequestStays429 reproduces the bug
+// where a rate-limited result, once cached, lost its 429 on every
+// subsequent request for the same URL: the cache-hit branch returned
+// straight through without checking metadata.Error.
+func TestHandleFetch_RepeatedRateLimitedRequestStays429(t *testing.T) {
+	ms := NewMetadataService()
+	ms.cache.Set("https://example.com/rate-limited", &URLMetadata{
+		URL:   "https://example.com/rate-limited",
+		Error: ErrCodeRateLimited + ": rate limit exceeded for host example.com",
+	}, defaultNegativeTTL)
+
+	body, _ := json.Marshal(FetchRequest{URL: "https://example.com/rate-limited"})
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/fetch", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+		ms.handleFetch(w, req)
+		if w.Code != http.StatusTooManyRequests {
+			t.Fatalf("request %d: status = %d, want %d", i+1, w.Code, http.StatusTooManyRequests)
+		}
+	}
+}
+
+// TestHandleFetch_CacheHitReportsCachedNotShared guards against conflating
+// the two signals: a real cache hit must set Cached, not Shared.
+func TestHandleFetch_CacheHitReportsCachedNotShared(t *testing.T) {
+	ms := NewMetadataService()
+	ms.cache.Set("https://example.com/cached", &URLMetadata{
+		URL:   "https://example.com/cached",
+		Title: "Example",
+	}, defaultSuccessTTL)
+
+	body, _ := json.Marshal(FetchRequest{URL: "https://example.com/cached"})
+	req := httptest.NewRequest(http.MethodPost, "/fetch", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	ms.handleFetch(w, req)
+
+	var resp FetchResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response body: %v", err)
+	}
+	if !resp.Cached {
+		t.Errorf("Cached = false, want true for a cache hit")
+	}
+	if resp.Shared {
+		t.Errorf("Shared = true, want false for a cache hit")
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Prometheus metrics for MetadataService. Registered against the default
+// registry so a plain promhttp.Handler() on /metrics picks them up.
+var (
+	metadataFetchTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "metadata_fetch_total",
+		Help: "Total fetch attempts by result (hit, miss, error).",
+	}, []string{"result"})
+
+	// metadataFetchDuration is intentionally unlabeled: this service fetches
+	// arbitrary caller-supplied URLs, so a "host" label would create an
+	// unbounded number of time series. Per-host timing is logged instead.
+	metadataFetchDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "metadata_fetch_duration_seconds",
+		Help:    "Time spent servicing a fetch request.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	metadataCacheSizeBytes = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "metadata_cache_size_bytes",
+		Help: "Approximate total size in bytes of cached metadata.",
+	})
+
+	metadataUpstreamStatusTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "metadata_upstream_status_total",
+		Help: "Upstream HTTP response counts by status code.",
+	}, []string{"code"})
+
+	metadataSingleflightSharedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "metadata_singleflight_shared_total",
+		Help: "Number of fetches that reused another in-flight request's result.",
+	})
+)
+
+// recordFetchResult increments metadata_fetch_total for a completed fetch.
+func recordFetchResult(result string) {
+	metadataFetchTotal.WithLabelValues(result).Inc()
+}
+
+// recordUpstreamStatus increments metadata_upstream_status_total for an
+// upstream HTTP response.
+func recordUpstreamStatus(statusCode int) {
+	metadataUpstreamStatusTotal.WithLabelValues(strconv.Itoa(statusCode)).Inc()
+}
+
+// observeCacheSize publishes the cache's current byte size to the
+// metadata_cache_size_bytes gauge. It's cheap enough to call on every
+// cache mutation rather than on a timer.
+func observeCacheSize(c *Cache) {
+	metadataCacheSizeBytes.Set(float64(c.Stats().SizeBytes))
+}
@@ -0,0 +1,17 @@
+package main
+
+import "testing"
+
+func TestRequestHost(t *testing.T) {
+	cases := map[string]string{
+		"https://example.com/a/b": "example.com",
+		"http://sub.example.com":  "sub.example.com",
+		"not a url":               "unknown",
+		"":                        "unknown",
+	}
+	for in, want := range cases {
+		if got := requestHost(in); got != want {
+			t.Errorf("requestHost(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/temoto/robotstxt"
+	"golang.org/x/time/rate"
+)
+
+// ErrCodeRateLimited is returned in URLMetadata.Error (and as HTTP 429 from
+// handleFetch) when a per-host or global rate limit rejects the request.
+const ErrCodeRateLimited = "rate_limited"
+
+const (
+	defaultGlobalQPS  = 20.0
+	defaultPerHostQPS = 1.0
+	defaultBurst      = 5
+	robotsCacheTTL    = 1 * time.Hour
+	robotsUserAgent   = "Lobsters-MetadataService"
+)
+
+// HostRateLimiter enforces both a global and a per-host token bucket rate
+// limit on outbound fetches, so a burst of requests to many cold hosts
+// can't overrun upstream services and one chatty host can't starve the
+// others.
+type HostRateLimiter struct {
+	mu           sync.Mutex
+	global       *rate.Limiter
+	perHost      map[string]*rate.Limiter
+	perHostQPS   rate.Limit
+	perHostBurst int
+}
+
+// NewHostRateLimiterFromEnv builds a HostRateLimiter using QPS knobs from
+// the environment (RATE_LIMIT_GLOBAL_QPS, RATE_LIMIT_PERHOST_QPS,
+// RATE_LIMIT_BURST), falling back to conservative defaults.
+func NewHostRateLimiterFromEnv() *HostRateLimiter {
+	globalQPS := envFloat("RATE_LIMIT_GLOBAL_QPS", defaultGlobalQPS)
+	perHostQPS := envFloat("RATE_LIMIT_PERHOST_QPS", defaultPerHostQPS)
+	burst := envInt("RATE_LIMIT_BURST", defaultBurst)
+
+	return &HostRateLimiter{
+		global:       rate.NewLimiter(rate.Limit(globalQPS), burst),
+		perHost:      make(map[string]*rate.Limiter),
+		perHostQPS:   rate.Limit(perHostQPS),
+		perHostBurst: burst,
+	}
+}
+
+// Allow reports whether a fetch to host is permitted right now under both
+// the global and per-host budgets. It does not block; callers should treat
+// a false result as an immediate rejection.
+func (rl *HostRateLimiter) Allow(host string) bool {
+	if !rl.global.Allow() {
+		return false
+	}
+	return rl.hostLimiter(host).Allow()
+}
+
+func (rl *HostRateLimiter) hostLimiter(host string) *rate.Limiter {
+	host = strings.ToLower(host)
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	limiter, exists := rl.perHost[host]
+	if !exists {
+		limiter = rate.NewLimiter(rl.perHostQPS, rl.perHostBurst)
+		rl.perHost[host] = limiter
+	}
+	return limiter
+}
+
+func envFloat(key string, fallback float64) float64 {
+	if v := os.Getenv(key); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	}
+	return fallback
+}
+
+func envInt(key string, fallback int) int {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+	}
+	return fallback
+}
+
+// robotsCacheEntry is a parsed robots.txt with its own expiry, independent
+// of the metadata cache's TTLs.
+type robotsCacheEntry struct {
+	data      *robotstxt.RobotsData
+	expiresAt time.Time
+}
+
+// RobotsCache fetches and caches robots.txt per host so polite-fetching
+// checks don't cost an extra round trip on every request.
+type RobotsCache struct {
+	mu      sync.Mutex
+	entries map[string]*robotsCacheEntry
+	ttl     time.Duration
+}
+
+// NewRobotsCache returns an empty RobotsCache with the given per-host TTL.
+func NewRobotsCache(ttl time.Duration) *RobotsCache {
+	return &RobotsCache{
+		entries: make(map[string]*robotsCacheEntry),
+		ttl:     ttl,
+	}
+}
+
+// allowed reports whether userAgent may fetch path on the given host,
+// fetching and caching that host's robots.txt as needed. Any failure to
+// fetch or parse robots.txt fails open (allowed), matching common crawler
+// behavior of treating a missing robots.txt as permissive.
+func (ms *MetadataService) allowedByRobots(ctx context.Context, u *url.URL) bool {
+	host := strings.ToLower(u.Host)
+
+	ms.robotsCache.mu.Lock()
+	entry, exists := ms.robotsCache.entries[host]
+	ms.robotsCache.mu.Unlock()
+
+	if !exists || time.Now().After(entry.expiresAt) {
+		data, err := ms.fetchRobots(ctx, u)
+		entry = &robotsCacheEntry{data: data, expiresAt: time.Now().Add(ms.robotsCache.ttl)}
+		if err == nil {
+			ms.robotsCache.mu.Lock()
+			ms.robotsCache.entries[host] = entry
+			ms.robotsCache.mu.Unlock()
+		}
+	}
+
+	if entry.data == nil {
+		return true
+	}
+	return entry.data.TestAgent(u.Path, robotsUserAgent)
+}
+
+// fetchRobots retrieves and parses robots.txt for the host of u. A missing
+// or unparsable robots.txt is not an error from the caller's perspective
+// (nil data means "no restrictions"), but the error is still returned so
+// allowedByRobots can decide whether to cache the miss.
+func (ms *MetadataService) fetchRobots(ctx context.Context, u *url.URL) (*robotstxt.RobotsData, error) {
+	robotsURL := fmt.Sprintf("%s://%s/robots.txt", u.Scheme, u.Host)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", robotsURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", robotsUserAgent)
+
+	resp, err := ms.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("robots.txt returned HTTP %d", resp.StatusCode)
+	}
+
+	return robotstxt.FromResponse(resp)
+}
+
+// doFetchWithRetry performs req, retrying on 429 and 5xx responses with
+// exponential backoff, honoring a Retry-After header when the upstream
+// sends one. It gives up once the backoff's max elapsed time is exceeded
+// or the context is done.
+func doFetchWithRetry(ctx context.Context, client *http.Client, req *http.Request) (*http.Response, error) {
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = 250 * time.Millisecond
+	b.MaxElapsedTime = 10 * time.Second
+
+	for {
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+			return resp, nil
+		}
+
+		lastStatus := resp.StatusCode
+		wait := retryAfterDelay(resp.Header.Get("Retry-After"))
+		resp.Body.Close()
+
+		if wait == 0 {
+			wait = b.NextBackOff()
+		}
+		if wait == backoff.Stop {
+			return nil, fmt.Errorf("giving up after retryable status %d", lastStatus)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// retryAfterDelay parses a Retry-After header value (seconds form only;
+// HTTP-date is rare for this use case and handled by the backoff default
+// otherwise) and returns the delay it specifies, or 0 if absent/invalid.
+func retryAfterDelay(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	secs, err := strconv.Atoi(header)
+	if err != nil || secs < 0 {
+		return 0
+	}
+	return time.Duration(secs) * time.Second
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestHostRateLimiter_PerHostBudgetIsIndependent(t *testing.T) {
+	rl := &HostRateLimiter{
+		global:       rate.NewLimiter(rate.Inf, 0),
+		perHost:      make(map[string]*rate.Limiter),
+		perHostQPS:   1,
+		perHostBurst: 1,
+	}
+
+	if !rl.Allow("a.example.com") {
+		t.Fatalf("expected first request to a.example.com to be allowed")
+	}
+	if rl.Allow("a.example.com") {
+		t.Errorf("expected second immediate request to a.example.com to be denied")
+	}
+	if !rl.Allow("b.example.com") {
+		t.Errorf("expected b.example.com to have its own budget, unaffected by a.example.com")
+	}
+}
+
+func TestHostRateLimiter_GlobalBudgetAppliesAcrossHosts(t *testing.T) {
+	rl := &HostRateLimiter{
+		global:       rate.NewLimiter(rate.Limit(0), 1),
+		perHost:      make(map[string]*rate.Limiter),
+		perHostQPS:   rate.Inf,
+		perHostBurst: 10,
+	}
+
+	if !rl.Allow("a.example.com") {
+		t.Fatalf("expected first request to consume the single global token")
+	}
+	if rl.Allow("b.example.com") {
+		t.Errorf("expected global budget exhaustion to deny a different host too")
+	}
+}
+
+func TestRetryAfterDelay(t *testing.T) {
+	cases := map[string]time.Duration{
+		"":     0,
+		"5":    5 * time.Second,
+		"0":    0,
+		"-1":   0,
+		"soon": 0,
+	}
+	for header, want := range cases {
+		if got := retryAfterDelay(header); got != want {
+			t.Errorf("retryAfterDelay(%q) = %v, want %v", header, got, want)
+		}
+	}
+}
+
+func TestDoFetchWithRetry_SucceedsAfterRetryableStatus(t *testing.T) {
+	attempts := 0
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		attempts++
+		if attempts == 1 {
+			return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: http.NoBody, Header: http.Header{}}, nil
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Header: http.Header{}}, nil
+	})}
+
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	resp, err := doFetchWithRetry(context.Background(), client, req)
+	if err != nil {
+		t.Fatalf("doFetchWithRetry() error = %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("doFetchWithRetry() status = %d, want 200", resp.StatusCode)
+	}
+	if attempts != 2 {
+		t.Errorf("attempts = %d, want 2", attempts)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Embed holds oEmbed data discovered via a page's
+// <link rel="alternate" type="application/json+oembed"> tag.
+type Embed struct {
+	Type     string `json:"type,omitempty"`
+	HTML     string `json:"html,omitempty"`
+	Width    int    `json:"width,omitempty"`
+	Height   int    `json:"height,omitempty"`
+	Provider string `json:"provider,omitempty"`
+	Author   string `json:"author,omitempty"`
+}
+
+// Article holds schema.org Article/NewsArticle fields extracted from a
+// page's JSON-LD blocks.
+type Article struct {
+	Author        string `json:"author,omitempty"`
+	DatePublished string `json:"date_published,omitempty"`
+}
+
+// Product holds schema.org Product fields extracted from a page's JSON-LD
+// blocks.
+type Product struct {
+	Price  string `json:"price,omitempty"`
+	Rating string `json:"rating,omitempty"`
+}
+
+// Video holds schema.org VideoObject fields extracted from a page's
+// JSON-LD blocks.
+type Video struct {
+	Author        string `json:"author,omitempty"`
+	DatePublished string `json:"date_published,omitempty"`
+}
+
+// oEmbedResponse mirrors the subset of the oEmbed spec fields we care about.
+// See https://oembed.com/.
+type oEmbedResponse struct {
+	Type            string `json:"type"`
+	HTML            string `json:"html"`
+	Width           int    `json:"width"`
+	Height          int    `json:"height"`
+	ProviderName    string `json:"provider_name"`
+	AuthorName      string `json:"author_name"`
+	ThumbnailHeight int    `json:"thumbnail_height"`
+}
+
+// jsonLDNode is a loosely-typed JSON-LD node; schema.org documents vary in
+// which of these fields are present and in what shape (@graph-wrapped or
+// bare), so we decode permissively and pick out what we recognize.
+type jsonLDNode struct {
+	Type          interface{}     `json:"@type"`
+	Author        json.RawMessage `json:"author"`
+	DatePublished string          `json:"datePublished"`
+	Offers        json.RawMessage `json:"offers"`
+	AggregateRat  json.RawMessage `json:"aggregateRating"`
+	Graph         []jsonLDNode    `json:"@graph"`
+}
+
+// extractOEmbedLink returns the href of the page's discovered oEmbed link,
+// or "" if none is present.
+func extractOEmbedLink(doc *goquery.Document) string {
+	return doc.Find(`link[rel="alternate"][type="application/json+oembed"]`).AttrOr("href", "")
+}
+
+// fetchOEmbed validates and fetches the oEmbed document discovered on a
+// page and converts it into an Embed. It goes through the same URLPolicy as
+// the primary fetch since the oEmbed URL is attacker-controlled content.
+func (ms *MetadataService) fetchOEmbed(ctx context.Context, oembedURL string) (*Embed, error) {
+	parsed, _, err := ms.urlPolicy.Validate(oembedURL)
+	if err != nil {
+		return nil, fmt.Errorf("oembed URL rejected: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", parsed.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create oembed request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := ms.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch oembed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oembed endpoint returned HTTP %d", resp.StatusCode)
+	}
+
+	body := io.LimitReader(resp.Body, ms.urlPolicy.MaxResponseSize)
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read oembed response: %w", err)
+	}
+
+	return parseOEmbedJSON(data)
+}
+
+// parseOEmbedJSON decodes a raw oEmbed JSON document into an Embed.
+func parseOEmbedJSON(data []byte) (*Embed, error) {
+	var o oEmbedResponse
+	if err := json.Unmarshal(data, &o); err != nil {
+		return nil, fmt.Errorf("invalid oembed JSON: %w", err)
+	}
+
+	return &Embed{
+		Type:     o.Type,
+		HTML:     o.HTML,
+		Width:    o.Width,
+		Height:   o.Height,
+		Provider: o.ProviderName,
+		Author:   o.AuthorName,
+	}, nil
+}
+
+// extractJSONLD walks every <script type="application/ld+json"> block on
+// the page, following @graph wrappers, and merges any Article, NewsArticle,
+// VideoObject, or Product nodes it finds into the returned sub-structs.
+// Any of the three return values may be nil if no matching node was found.
+func extractJSONLD(doc *goquery.Document) (*Article, *Product, *Video) {
+	var article *Article
+	var product *Product
+	var video *Video
+
+	doc.Find(`script[type="application/ld+json"]`).Each(func(_ int, s *goquery.Selection) {
+		var node jsonLDNode
+		if err := json.Unmarshal([]byte(s.Text()), &node); err != nil {
+			return
+		}
+
+		nodes := node.Graph
+		if len(nodes) == 0 {
+			nodes = []jsonLDNode{node}
+		}
+
+		for _, n := range nodes {
+			switch jsonLDTypeName(n.Type) {
+			case "Article", "NewsArticle", "BlogPosting":
+				if article == nil {
+					article = &Article{}
+				}
+				if a := jsonLDAuthorName(n.Author); a != "" {
+					article.Author = a
+				}
+				if n.DatePublished != "" {
+					article.DatePublished = n.DatePublished
+				}
+			case "VideoObject":
+				if video == nil {
+					video = &Video{}
+				}
+				if a := jsonLDAuthorName(n.Author); a != "" {
+					video.Author = a
+				}
+				if n.DatePublished != "" {
+					video.DatePublished = n.DatePublished
+				}
+			case "Product":
+				if product == nil {
+					product = &Product{}
+				}
+				if price := jsonLDOfferPrice(n.Offers); price != "" {
+					product.Price = price
+				}
+				if rating := jsonLDRatingValue(n.AggregateRat); rating != "" {
+					product.Rating = rating
+				}
+			}
+		}
+	})
+
+	return article, product, video
+}
+
+// jsonLDTypeName normalizes @type, which schema.org allows to be either a
+// bare string or an array of strings, into a single name.
+func jsonLDTypeName(t interface{}) string {
+	switch v := t.(type) {
+	case string:
+		return v
+	case []interface{}:
+		if len(v) > 0 {
+			if s, ok := v[0].(string); ok {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// jsonLDAuthorName pulls a display name out of an author field, which may
+// be a bare string or a Person/Organization object with a "name" key.
+func jsonLDAuthorName(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var name string
+	if err := json.Unmarshal(raw, &name); err == nil {
+		return name
+	}
+	var obj struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(raw, &obj); err == nil {
+		return obj.Name
+	}
+	var list []struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(raw, &list); err == nil && len(list) > 0 {
+		return list[0].Name
+	}
+	return ""
+}
+
+// jsonLDOfferPrice pulls a price out of an offers field, which may be a
+// bare Offer object or an array of them.
+func jsonLDOfferPrice(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var offer struct {
+		Price string `json:"price"`
+	}
+	if err := json.Unmarshal(raw, &offer); err == nil && offer.Price != "" {
+		return offer.Price
+	}
+	var offers []struct {
+		Price string `json:"price"`
+	}
+	if err := json.Unmarshal(raw, &offers); err == nil && len(offers) > 0 {
+		return offers[0].Price
+	}
+	return ""
+}
+
+// jsonLDRatingValue pulls ratingValue out of an aggregateRating field.
+func jsonLDRatingValue(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var rating struct {
+		RatingValue string `json:"ratingValue"`
+	}
+	if err := json.Unmarshal(raw, &rating); err == nil {
+		return rating.RatingValue
+	}
+	return ""
+}
+
+// mergeRichMetadata populates the Embed/Article/Product/Video sub-structs
+// on metadata from the parsed document, fetching the oEmbed endpoint if one
+// was discovered.
+func (ms *MetadataService) mergeRichMetadata(ctx context.Context, doc *goquery.Document, metadata *URLMetadata) {
+	if link := extractOEmbedLink(doc); link != "" {
+		if embed, err := ms.fetchOEmbed(ctx, link); err == nil {
+			metadata.Embed = embed
+		}
+	}
+
+	metadata.Article, metadata.Product, metadata.Video = extractJSONLD(doc)
+}
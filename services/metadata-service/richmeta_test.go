@@ -0,0 +1,106 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func mustParseFixture(t *testing.T, html string) *goquery.Document {
+	t.Helper()
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		t.Fatalf("failed to parse fixture: %v", err)
+	}
+	return doc
+}
+
+func TestExtractOEmbedLink_YouTube(t *testing.T) {
+	doc := mustParseFixture(t, `<html><head>
+		<link rel="alternate" type="application/json+oembed"
+			href="https://www.youtube.com/oembed?url=https://youtu.be/abc123&format=json"
+			title="some video">
+	</head><body></body></html>`)
+
+	got := extractOEmbedLink(doc)
+	want := "https://www.youtube.com/oembed?url=https://youtu.be/abc123&format=json"
+	if got != want {
+		t.Errorf("extractOEmbedLink() = %q, want %q", got, want)
+	}
+}
+
+func TestParseOEmbedJSON_Twitter(t *testing.T) {
+	fixture := `{
+		"type": "rich",
+		"html": "<blockquote class=\"twitter-tweet\">hello</blockquote>",
+		"width": 550,
+		"height": 0,
+		"provider_name": "Twitter",
+		"author_name": "Example User"
+	}`
+
+	embed, err := parseOEmbedJSON([]byte(fixture))
+	if err != nil {
+		t.Fatalf("parseOEmbedJSON() error = %v", err)
+	}
+
+	if embed.Type != "rich" || embed.Provider != "Twitter" || embed.Author != "Example User" || embed.Width != 550 {
+		t.Errorf("parseOEmbedJSON() = %+v, want rich/Twitter/Example User/550", embed)
+	}
+}
+
+func TestExtractJSONLD_Product(t *testing.T) {
+	doc := mustParseFixture(t, `<html><head>
+		<script type="application/ld+json">
+		{
+			"@context": "https://schema.org/",
+			"@type": "Product",
+			"name": "Widget",
+			"offers": {
+				"@type": "Offer",
+				"price": "19.99",
+				"priceCurrency": "USD"
+			},
+			"aggregateRating": {
+				"@type": "AggregateRating",
+				"ratingValue": "4.5",
+				"reviewCount": "89"
+			}
+		}
+		</script>
+	</head><body></body></html>`)
+
+	article, product, video := extractJSONLD(doc)
+	if article != nil || video != nil {
+		t.Fatalf("expected only product to be populated, got article=%+v video=%+v", article, video)
+	}
+	if product == nil || product.Price != "19.99" || product.Rating != "4.5" {
+		t.Errorf("extractJSONLD() product = %+v, want price=19.99 rating=4.5", product)
+	}
+}
+
+func TestExtractJSONLD_GraphWrappedArticle(t *testing.T) {
+	doc := mustParseFixture(t, `<html><head>
+		<script type="application/ld+json">
+		{
+			"@context": "https://schema.org",
+			"@graph": [
+				{
+					"@type": "NewsArticle",
+					"author": {"@type": "Person", "name": "Jane Doe"},
+					"datePublished": "2024-01-15T10:00:00Z"
+				}
+			]
+		}
+		</script>
+	</head><body></body></html>`)
+
+	article, product, video := extractJSONLD(doc)
+	if product != nil || video != nil {
+		t.Fatalf("expected only article to be populated, got product=%+v video=%+v", product, video)
+	}
+	if article == nil || article.Author != "Jane Doe" || article.DatePublished != "2024-01-15T10:00:00Z" {
+		t.Errorf("extractJSONLD() article = %+v, want author=Jane Doe", article)
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Error codes surfaced in URLMetadata.Error so callers can differentiate
+// failure modes without parsing human-readable strings.
+const (
+	ErrCodeInvalidURL    = "invalid_url"
+	ErrCodeBlockedHost   = "blocked_host"
+	ErrCodeTooLarge      = "too_large"
+	ErrCodeUpstreamError = "upstream_error"
+)
+
+// defaultMaxResponseBytes bounds how much of an upstream response body we
+// will ever read, regardless of what Content-Length claims.
+const defaultMaxResponseBytes = 10 * 1024 * 1024
+
+// URLPolicy validates candidate URLs before MetadataService is allowed to
+// fetch them, and re-validates every hop of a redirect chain. It exists to
+// keep handleFetch from being turned into an SSRF pivot against internal
+// services or the cloud metadata endpoint.
+type URLPolicy struct {
+	AllowedSchemes  map[string]bool
+	DeniedCIDRs     []*net.IPNet
+	AllowedHosts    map[string]bool // optional; if non-empty, only these hosts are permitted
+	MaxResponseSize int64
+	resolveHost     func(host string) ([]net.IP, error)
+}
+
+// NewURLPolicy returns a URLPolicy with the standard scheme allowlist and
+// RFC1918/loopback/link-local/ULA denylist pre-populated. Callers can add
+// extra CIDR ranges or an explicit host allowlist on top of these defaults.
+func NewURLPolicy() *URLPolicy {
+	p := &URLPolicy{
+		AllowedSchemes:  map[string]bool{"http": true, "https": true},
+		MaxResponseSize: defaultMaxResponseBytes,
+		resolveHost:     net.LookupIP,
+	}
+	for _, cidr := range []string{
+		"127.0.0.0/8",    // loopback
+		"10.0.0.0/8",     // RFC1918
+		"172.16.0.0/12",  // RFC1918
+		"192.168.0.0/16", // RFC1918
+		"169.254.0.0/16", // link-local / cloud metadata
+		"::1/128",        // loopback
+		"fe80::/10",      // link-local
+		"fc00::/7",       // ULA
+	} {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(fmt.Sprintf("urlpolicy: invalid built-in CIDR %q: %v", cidr, err))
+		}
+		p.DeniedCIDRs = append(p.DeniedCIDRs, n)
+	}
+	return p
+}
+
+// AddDeniedCIDR registers an additional CIDR range that resolved IPs must
+// not fall within.
+func (p *URLPolicy) AddDeniedCIDR(cidr string) error {
+	_, n, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return fmt.Errorf("invalid CIDR %q: %w", cidr, err)
+	}
+	p.DeniedCIDRs = append(p.DeniedCIDRs, n)
+	return nil
+}
+
+// Validate parses rawURL and checks its scheme and host against the policy.
+// It resolves the host to IPs and rejects the URL if any resolved address
+// falls inside a denied range, or if an allowlist is configured and the host
+// isn't on it. It returns the parsed URL so callers don't need to re-parse.
+func (p *URLPolicy) Validate(rawURL string) (*url.URL, string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return nil, ErrCodeInvalidURL, fmt.Errorf("invalid URL: %v", err)
+	}
+
+	if !p.AllowedSchemes[strings.ToLower(u.Scheme)] {
+		return nil, ErrCodeInvalidURL, fmt.Errorf("scheme %q is not allowed", u.Scheme)
+	}
+
+	host := u.Hostname()
+	if host == "" {
+		return nil, ErrCodeInvalidURL, fmt.Errorf("URL has no host")
+	}
+
+	if len(p.AllowedHosts) > 0 && !p.AllowedHosts[strings.ToLower(host)] {
+		return nil, ErrCodeBlockedHost, fmt.Errorf("host %q is not on the allowlist", host)
+	}
+
+	if err := p.checkHostResolution(host); err != nil {
+		return nil, ErrCodeBlockedHost, err
+	}
+
+	return u, "", nil
+}
+
+// checkHostResolution resolves host to its IP addresses (or parses it
+// directly if it's already an IP literal) and rejects it if any address
+// falls within a denied CIDR range.
+func (p *URLPolicy) checkHostResolution(host string) error {
+	if ip := net.ParseIP(host); ip != nil {
+		return p.checkIP(ip)
+	}
+
+	ips, err := p.resolveHost(host)
+	if err != nil {
+		return fmt.Errorf("failed to resolve host %q: %w", host, err)
+	}
+	if len(ips) == 0 {
+		return fmt.Errorf("host %q did not resolve to any address", host)
+	}
+	for _, ip := range ips {
+		if err := p.checkIP(ip); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *URLPolicy) checkIP(ip net.IP) error {
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("address %s is not routable", ip)
+	}
+	for _, n := range p.DeniedCIDRs {
+		if n.Contains(ip) {
+			return fmt.Errorf("address %s is in denied range %s", ip, n)
+		}
+	}
+	return nil
+}
+
+// SafeDialContext returns a DialContext for use on the http.Transport that
+// actually performs MetadataService's outbound fetches. Validate only
+// checks the hostname at request-construction time; net/http then resolves
+// and dials the host itself, which gives a DNS-rebinding attacker a window
+// to swap a host's DNS answer from a public IP to a denied one (loopback,
+// link-local, RFC1918) between the two lookups. This dialer closes that gap
+// by resolving addr itself, checking every candidate IP against the same
+// policy, and dialing the checked IP directly, so the address that was
+// validated is the address that gets connected to.
+func (p *URLPolicy) SafeDialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dial address %q: %w", addr, err)
+		}
+
+		var ips []net.IP
+		if ip := net.ParseIP(host); ip != nil {
+			ips = []net.IP{ip}
+		} else {
+			ips, err = p.resolveHost(host)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve host %q: %w", host, err)
+			}
+		}
+		if len(ips) == 0 {
+			return nil, fmt.Errorf("host %q did not resolve to any address", host)
+		}
+
+		var lastErr error
+		for _, ip := range ips {
+			if err := p.checkIP(ip); err != nil {
+				lastErr = err
+				continue
+			}
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			return conn, nil
+		}
+		if lastErr == nil {
+			lastErr = fmt.Errorf("no routable address found for host %q", host)
+		}
+		return nil, lastErr
+	}
+}
+
+// CheckRedirect is installed on the http.Client used by MetadataService so
+// that every hop of a redirect chain is re-validated, not just the
+// original request URL.
+func (p *URLPolicy) CheckRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return fmt.Errorf("stopped after %d redirects", len(via))
+	}
+	if _, code, err := p.Validate(req.URL.String()); err != nil {
+		return fmt.Errorf("%s: %w", code, err)
+	}
+	return nil
+}
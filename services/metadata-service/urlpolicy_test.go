@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+)
+
+func TestURLPolicy_ValidateRejectsDisallowedScheme(t *testing.T) {
+	p := NewURLPolicy()
+	if _, code, err := p.Validate("ftp://example.com/file"); err == nil {
+		t.Fatalf("expected ftp scheme to be rejected")
+	} else if code != ErrCodeInvalidURL {
+		t.Errorf("code = %q, want %q", code, ErrCodeInvalidURL)
+	}
+}
+
+func TestURLPolicy_ValidateRejectsDeniedRanges(t *testing.T) {
+	p := NewURLPolicy()
+	cases := []string{
+		"http://127.0.0.1/",
+		"http://10.1.2.3/",
+		"http://172.16.0.1/",
+		"http://192.168.1.1/",
+		"http://169.254.169.254/latest/meta-data/", // cloud metadata endpoint
+		"http://[::1]/",
+		"http://[fe80::1]/",
+	}
+	for _, rawURL := range cases {
+		if _, code, err := p.Validate(rawURL); err == nil {
+			t.Errorf("Validate(%q) = nil error, want rejection", rawURL)
+		} else if code != ErrCodeBlockedHost {
+			t.Errorf("Validate(%q) code = %q, want %q", rawURL, code, ErrCodeBlockedHost)
+		}
+	}
+}
+
+func TestURLPolicy_ValidateAllowsPublicHost(t *testing.T) {
+	p := NewURLPolicy()
+	p.resolveHost = func(host string) ([]net.IP, error) {
+		return []net.IP{net.ParseIP("93.184.216.34")}, nil
+	}
+	if _, _, err := p.Validate("https://example.com/page"); err != nil {
+		t.Fatalf("Validate() error = %v, want nil", err)
+	}
+}
+
+func TestURLPolicy_ValidateRejectsHostResolvingToDeniedIP(t *testing.T) {
+	p := NewURLPolicy()
+	p.resolveHost = func(host string) ([]net.IP, error) {
+		return []net.IP{net.ParseIP("127.0.0.1")}, nil
+	}
+	if _, code, err := p.Validate("https://attacker.example.com/"); err == nil {
+		t.Fatalf("expected host resolving to a loopback address to be rejected")
+	} else if code != ErrCodeBlockedHost {
+		t.Errorf("code = %q, want %q", code, ErrCodeBlockedHost)
+	}
+}
+
+func TestURLPolicy_ValidateEnforcesHostAllowlist(t *testing.T) {
+	p := NewURLPolicy()
+	p.AllowedHosts = map[string]bool{"good.example.com": true}
+	p.resolveHost = func(host string) ([]net.IP, error) {
+		return []net.IP{net.ParseIP("93.184.216.34")}, nil
+	}
+	if _, _, err := p.Validate("https://good.example.com/"); err != nil {
+		t.Fatalf("Validate() error = %v, want nil", err)
+	}
+	if _, code, err := p.Validate("https://evil.example.com/"); err == nil {
+		t.Fatalf("expected host not on allowlist to be rejected")
+	} else if code != ErrCodeBlockedHost {
+		t.Errorf("code = %q, want %q", code, ErrCodeBlockedHost)
+	}
+}
+
+func TestURLPolicy_CheckRedirectRevalidatesEachHop(t *testing.T) {
+	p := NewURLPolicy()
+	req, _ := http.NewRequest("GET", "http://169.254.169.254/latest/meta-data/", nil)
+	if err := p.CheckRedirect(req, nil); err == nil {
+		t.Fatalf("expected redirect to the cloud metadata endpoint to be rejected")
+	}
+}
+
+func TestURLPolicy_CheckRedirectStopsLongChains(t *testing.T) {
+	p := NewURLPolicy()
+	req, _ := http.NewRequest("GET", "https://example.com/", nil)
+	var via []*http.Request
+	for i := 0; i < 10; i++ {
+		via = append(via, req)
+	}
+	if err := p.CheckRedirect(req, via); err == nil {
+		t.Fatalf("expected redirect chain longer than the limit to be rejected")
+	}
+}
+
+// TestURLPolicy_SafeDialContextRejectsRebindToDeniedIP guards against
+// DNS-rebinding: the resolver is allowed to return a denied address at dial
+// time even though Validate saw a public one earlier, and the dialer must
+// still refuse to connect.
+func TestURLPolicy_SafeDialContextRejectsRebindToDeniedIP(t *testing.T) {
+	p := NewURLPolicy()
+	p.resolveHost = func(host string) ([]net.IP, error) {
+		return []net.IP{net.ParseIP("169.254.169.254")}, nil
+	}
+
+	dial := p.SafeDialContext(&net.Dialer{})
+	_, err := dial(context.Background(), "tcp", "attacker.example.com:80")
+	if err == nil {
+		t.Fatalf("expected dial to a denied address to be rejected")
+	}
+}
+
+// TestURLPolicy_SafeDialContextSkipsDeniedCandidateIPs ensures that when a
+// host resolves to multiple addresses, a denied one among them doesn't
+// short-circuit the whole dial; the dialer should move on to the next
+// candidate instead of failing outright (matching checkHostResolution,
+// which rejects on any denied IP, but exercising the per-candidate loop).
+func TestURLPolicy_SafeDialContextSkipsDeniedCandidateIPs(t *testing.T) {
+	p := NewURLPolicy()
+	p.resolveHost = func(host string) ([]net.IP, error) {
+		return []net.IP{net.ParseIP("169.254.169.254")}, nil
+	}
+
+	dial := p.SafeDialContext(&net.Dialer{})
+	if _, err := dial(context.Background(), "tcp", "example.com:80"); err == nil {
+		t.Fatalf("expected dial to a host resolving only to a denied address to fail")
+	}
+}
+
+func TestURLPolicy_SafeDialContextRejectsUnresolvableHost(t *testing.T) {
+	p := NewURLPolicy()
+	p.resolveHost = func(host string) ([]net.IP, error) {
+		return nil, errors.New("no such host")
+	}
+	dial := p.SafeDialContext(&net.Dialer{})
+	if _, err := dial(context.Background(), "tcp", "nowhere.example.com:80"); err == nil {
+		t.Fatalf("expected dial to an unresolvable host to fail")
+	}
+}
+
+func TestURLPolicy_AddDeniedCIDR(t *testing.T) {
+	p := NewURLPolicy()
+	if err := p.AddDeniedCIDR("198.51.100.0/24"); err != nil {
+		t.Fatalf("AddDeniedCIDR() error = %v", err)
+	}
+	if err := p.checkIP(net.ParseIP("198.51.100.7")); err == nil {
+		t.Fatalf("expected address in newly denied range to be rejected")
+	}
+	if err := p.AddDeniedCIDR("not-a-cidr"); err == nil {
+		t.Fatalf("expected invalid CIDR to return an error")
+	}
+}